@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Sink receives a Snapshot each time a Collector ticks.
+type Sink interface {
+	Emit(snapshot Snapshot) error
+}
+
+// FuncSink adapts a plain function to the Sink interface.
+type FuncSink func(Snapshot) error
+
+func (f FuncSink) Emit(snapshot Snapshot) error { return f(snapshot) }
+
+// WriterSink encodes each Snapshot in Format and writes it to W.
+type WriterSink struct {
+	W      io.Writer
+	Format Format
+}
+
+func (w WriterSink) Emit(snapshot Snapshot) error {
+	_, err := w.W.Write(snapshot.Encode(w.Format))
+	return err
+}
+
+// HTTPSink encodes each Snapshot in Format and sends it to URL via Method
+// (POST if unset), with Headers applied to the request.
+type HTTPSink struct {
+	URL     string
+	Method  string
+	Format  Format
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (h HTTPSink) Emit(snapshot Snapshot) error {
+	method := h.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, h.URL, bytes.NewReader(snapshot.Encode(h.Format)))
+	if err != nil {
+		return err
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Collector drives a Stats instance on a ticker: each interval it snapshots
+// the stats, resets them, and fans the snapshot out to every Sink
+// concurrently. OnError, if set, is called with any error a Sink's Emit
+// returns; a nil OnError discards sink errors.
+type Collector struct {
+	stats    *Stats
+	interval time.Duration
+	sinks    []Sink
+	OnError  func(error)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCollector builds a Collector over s, ticking at interval and fanning
+// each snapshot out to sinks.
+func NewCollector(s *Stats, interval time.Duration, sinks ...Sink) *Collector {
+	return &Collector{stats: s, interval: interval, sinks: sinks}
+}
+
+// Start runs the collector on its own goroutine until ctx is cancelled or
+// Stop is called. If the collector is already running, Start stops that
+// run first, so restarting it (e.g. after a config reload) can't leak the
+// previous goroutine.
+func (c *Collector) Start(ctx context.Context) {
+	c.Stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.mu.Lock()
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	c.mu.Unlock()
+
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.tick()
+			}
+		}
+	}()
+}
+
+// Stop cancels the collector and waits for its goroutine to exit.
+func (c *Collector) Stop() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// tick atomically snapshots and resets stats under a single write lock, so
+// no write landing between the two is lost, then fans the snapshot out to
+// every sink concurrently so one slow sink can't stall the others.
+func (c *Collector) tick() {
+	snap := c.stats.snapshotAndReset()
+
+	var wg sync.WaitGroup
+	for _, sink := range c.sinks {
+		wg.Add(1)
+		go func(sink Sink) {
+			defer wg.Done()
+			if err := sink.Emit(snap); err != nil && c.OnError != nil {
+				c.OnError(err)
+			}
+		}(sink)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSnapshotAndResetIsAtomic guards against AddCounter calls landing
+// between a Collector tick's snapshot and its reset and being silently
+// dropped, which snapshot()+Reset() as two separate locked sections
+// allowed.
+func TestSnapshotAndResetIsAtomic(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+
+	const writes = 20000
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			s.AddCounter("hits", 1)
+		}
+	}()
+
+	var recovered int64
+	for i := 0; i < writes; i++ {
+		snap := s.snapshotAndReset()
+		if v, ok := snap.Values["hits"].(int64); ok {
+			recovered += v
+		}
+	}
+	wg.Wait()
+
+	// The writer may still have increments in flight after the last
+	// snapshotAndReset call above; one final call picks up the remainder.
+	final := s.snapshotAndReset()
+	if v, ok := final.Values["hits"].(int64); ok {
+		recovered += v
+	}
+
+	if recovered != writes {
+		t.Fatalf("recovered %d counter increments across snapshots, want %d", recovered, writes)
+	}
+}
+
+// TestStartStopsPreviousRun guards against a second Start call leaking the
+// first run's goroutine: without stopping it first, the earlier ticker
+// keeps firing forever and Stop only ever cancels the most recent run.
+func TestStartStopsPreviousRun(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+
+	var mu sync.Mutex
+	var ticks int
+	sink := FuncSink(func(Snapshot) error {
+		mu.Lock()
+		ticks++
+		mu.Unlock()
+		return nil
+	})
+
+	c := NewCollector(s, 5*time.Millisecond, sink)
+	c.Start(context.Background())
+	c.Start(context.Background()) // restart; must not leak the first run's goroutine
+	time.Sleep(50 * time.Millisecond)
+	c.Stop()
+
+	mu.Lock()
+	afterStop := ticks
+	mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	final := ticks
+	mu.Unlock()
+
+	if final != afterStop {
+		t.Fatalf("tick count kept rising after Stop: %d -> %d; a previous Start's goroutine is still running", afterStop, final)
+	}
+}
+
+// TestCollectorFansOutToAllSinks guards against a tick skipping delivery to
+// any of its Sinks.
+func TestCollectorFansOutToAllSinks(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+
+	var mu sync.Mutex
+	var got []string
+	sinkA := FuncSink(func(Snapshot) error {
+		mu.Lock()
+		got = append(got, "a")
+		mu.Unlock()
+		return nil
+	})
+	sinkB := FuncSink(func(Snapshot) error {
+		mu.Lock()
+		got = append(got, "b")
+		mu.Unlock()
+		return nil
+	})
+
+	c := NewCollector(s, time.Hour, sinkA, sinkB)
+	c.tick()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 {
+		t.Fatalf("tick delivered to %d sinks, want 2: %v", len(got), got)
+	}
+}
+
+// TestCollectorOnErrorReceivesSinkErrors guards against a Sink error being
+// silently discarded when OnError is set, and against one failing Sink
+// stopping delivery to the others.
+func TestCollectorOnErrorReceivesSinkErrors(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+
+	wantErr := errors.New("boom")
+	failing := FuncSink(func(Snapshot) error { return wantErr })
+
+	var mu sync.Mutex
+	var emitted bool
+	ok := FuncSink(func(Snapshot) error {
+		mu.Lock()
+		emitted = true
+		mu.Unlock()
+		return nil
+	})
+
+	var gotErr error
+	c := NewCollector(s, time.Hour, failing, ok)
+	c.OnError = func(err error) { gotErr = err }
+	c.tick()
+
+	if gotErr != wantErr {
+		t.Fatalf("OnError got %v, want %v", gotErr, wantErr)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !emitted {
+		t.Fatalf("failing sink prevented delivery to the other sink")
+	}
+}
+
+func TestWriterSinkEmit(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+
+	var buf bytes.Buffer
+	sink := WriterSink{W: &buf, Format: FormatText}
+	if err := sink.Emit(s.Snapshot()); err != nil {
+		t.Fatalf("WriterSink.Emit returned %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("hits: 1")) {
+		t.Fatalf("WriterSink.Emit wrote %q, want it to contain the counter", buf.String())
+	}
+}
+
+func TestHTTPSinkEmit(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+
+	var gotBody bytes.Buffer
+	var gotMethod string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotBody.ReadFrom(r.Body)
+	}))
+	defer srv.Close()
+
+	sink := HTTPSink{URL: srv.URL, Format: FormatText}
+	if err := sink.Emit(s.Snapshot()); err != nil {
+		t.Fatalf("HTTPSink.Emit returned %v", err)
+	}
+	if gotMethod != http.MethodPost {
+		t.Fatalf("HTTPSink.Emit used method %q, want POST default", gotMethod)
+	}
+	if !bytes.Contains(gotBody.Bytes(), []byte("hits: 1")) {
+		t.Fatalf("HTTPSink.Emit posted %q, want it to contain the counter", gotBody.String())
+	}
+}
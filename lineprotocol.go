@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// Escapes commas, spaces and equals signs per the InfluxDB line protocol
+// rules for tag keys and values.
+func escapeLineProtocol(s string) string {
+	r := strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+	return r.Replace(s)
+}
+
+// Escapes commas and spaces per the InfluxDB line protocol rules for a
+// measurement name. Unlike tag/field keys and values, "=" is valid
+// unescaped in a measurement name.
+func escapeLineProtocolMeasurement(s string) string {
+	r := strings.NewReplacer(",", `\,`, " ", `\ `)
+	return r.Replace(s)
+}
+
+// Writes the current snapshot to w as a single InfluxDB line protocol point:
+// measurement, followed by the given tags, the stored metrics and the
+// elapsed Duration as fields, and a nanosecond timestamp from time.Now().
+func (s *Stats) LineProtocol(w Writer, measurement string, tags map[string]string) {
+	w.Write(s.snapshot().lineProtocol(measurement, tags))
+}
+
+// Returns the tag keys in sorted order so repeated calls produce a stable
+// line for the same tag set.
+func sortedTagKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
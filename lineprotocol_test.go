@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEscapeLineProtocol(t *testing.T) {
+	got := escapeLineProtocol(`a,b c=d`)
+	want := `a\,b\ c\=d`
+	if got != want {
+		t.Fatalf("escapeLineProtocol(%q) = %q, want %q", `a,b c=d`, got, want)
+	}
+}
+
+// TestEscapeLineProtocolMeasurement guards against "=" being escaped in a
+// measurement name, which is valid unescaped there per the InfluxDB line
+// protocol spec and would otherwise read back as a literal backslash.
+func TestEscapeLineProtocolMeasurement(t *testing.T) {
+	got := escapeLineProtocolMeasurement(`app=prod, east`)
+	want := `app=prod\,\ east`
+	if got != want {
+		t.Fatalf("escapeLineProtocolMeasurement(%q) = %q, want %q", `app=prod, east`, got, want)
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 3)
+
+	var buf bytes.Buffer
+	s.LineProtocol(&buf, "app=prod", map[string]string{"host": "a b"})
+	line := buf.String()
+
+	if !strings.HasPrefix(line, `app=prod,host=a\ b `) {
+		t.Fatalf("LineProtocol measurement/tags malformed: %q", line)
+	}
+	if !strings.Contains(line, "hits=3i") {
+		t.Fatalf("LineProtocol missing counter field: %q", line)
+	}
+	if !strings.Contains(line, "duration_ns=") {
+		t.Fatalf("LineProtocol missing duration field: %q", line)
+	}
+	if !strings.HasSuffix(line, "\n") {
+		t.Fatalf("LineProtocol line not newline-terminated: %q", line)
+	}
+}
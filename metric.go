@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format selects how a metric, or the Stats snapshot as a whole, is rendered
+// to text.
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatText
+	FormatPromText
+	FormatLineProto
+)
+
+// metric is implemented by every kind of value Stats can store. Snapshot
+// returns an immutable copy of the metric's current state, for use by
+// callers building their own encoders. Encode renders the metric under key
+// as a single, self-contained value in the given format.
+type metric interface {
+	Snapshot() interface{}
+	Encode(key string, format Format) string
+}
+
+// counterMetric is a monotonically increasing int64, the original behavior
+// of Add.
+type counterMetric struct {
+	v int64
+}
+
+func (c *counterMetric) Snapshot() interface{} { return c.v }
+
+func (c *counterMetric) Encode(key string, format Format) string {
+	return encodeCounter(key, c.v, format)
+}
+
+// encodeCounter renders a counter value under key. Shared by counterMetric
+// and Snapshot, which hold the same value live and copied, respectively.
+func encodeCounter(key string, v int64, format Format) string {
+	switch format {
+	case FormatJSON:
+		return fmt.Sprintf(`"%s": %d`, key, v)
+	case FormatPromText:
+		return fmt.Sprintf("# HELP %s %s\n# TYPE %s counter\n%s %d\n", key, key, key, key, v)
+	case FormatLineProto:
+		return fmt.Sprintf("%s=%di", escapeLineProtocol(key), v)
+	default:
+		return fmt.Sprintf("%s: %d", key, v)
+	}
+}
+
+// gaugeMetric is an arbitrary float64 that can move up or down.
+type gaugeMetric struct {
+	v float64
+}
+
+func (g *gaugeMetric) Snapshot() interface{} { return g.v }
+
+func (g *gaugeMetric) Encode(key string, format Format) string {
+	return encodeGauge(key, g.v, format)
+}
+
+// encodeGauge renders a gauge value under key. Shared by gaugeMetric and
+// Snapshot, which hold the same value live and copied, respectively.
+func encodeGauge(key string, v float64, format Format) string {
+	switch format {
+	case FormatJSON:
+		return fmt.Sprintf(`"%s": %g`, key, v)
+	case FormatPromText:
+		return fmt.Sprintf("# HELP %s %s\n# TYPE %s gauge\n%s %g\n", key, key, key, key, v)
+	case FormatLineProto:
+		return fmt.Sprintf("%s=%g", escapeLineProtocol(key), v)
+	default:
+		return fmt.Sprintf("%s: %g", key, v)
+	}
+}
+
+// histogramSnapshot is the immutable state returned by histogramMetric.Snapshot.
+type histogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64
+}
+
+// histogramMetric tracks observations against a fixed, ascending set of
+// bucket upper bounds, alongside a running count and sum, the same shape
+// Prometheus and most metrics backends expect.
+type histogramMetric struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// newHistogramMetric sorts buckets ascending and allocates one cumulative
+// counter per bucket.
+func newHistogramMetric(buckets []float64) *histogramMetric {
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+	return &histogramMetric{buckets: b, counts: make([]uint64, len(b))}
+}
+
+// observe records v, incrementing every bucket whose upper bound is at
+// least v as well as the overall count and sum.
+func (h *histogramMetric) observe(v float64) {
+	h.count++
+	h.sum += v
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+}
+
+func (h *histogramMetric) Snapshot() interface{} {
+	counts := make([]uint64, len(h.counts))
+	copy(counts, h.counts)
+	buckets := make([]float64, len(h.buckets))
+	copy(buckets, h.buckets)
+	return histogramSnapshot{Buckets: buckets, Counts: counts, Count: h.count, Sum: h.sum}
+}
+
+func (h *histogramMetric) Encode(key string, format Format) string {
+	return encodeHistogram(key, histogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  h.counts,
+		Count:   h.count,
+		Sum:     h.sum,
+	}, format)
+}
+
+// encodeHistogram renders a histogram's buckets, count and sum under key.
+// Shared by histogramMetric and Snapshot, which hold the same state live
+// and copied, respectively.
+func encodeHistogram(key string, snap histogramSnapshot, format Format) string {
+	switch format {
+	case FormatJSON:
+		buckets, _ := json.Marshal(snap.Buckets)
+		counts, _ := json.Marshal(snap.Counts)
+		return fmt.Sprintf(`"%s": {"count": %d, "sum": %g, "buckets": %s, "counts": %s}`,
+			key, snap.Count, snap.Sum, buckets, counts)
+	case FormatPromText:
+		var b strings.Builder
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", key, key, key)
+		for i, bound := range snap.Buckets {
+			fmt.Fprintf(&b, "%s_bucket{le=\"%g\"} %d\n", key, bound, snap.Counts[i])
+		}
+		fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} %d\n", key, snap.Count)
+		fmt.Fprintf(&b, "%s_count %d\n", key, snap.Count)
+		fmt.Fprintf(&b, "%s_sum %g\n", key, snap.Sum)
+		return b.String()
+	case FormatLineProto:
+		fields := make([]string, 0, len(snap.Buckets)+2)
+		for i, bound := range snap.Buckets {
+			fields = append(fields, fmt.Sprintf("%s_bucket_%g=%di", escapeLineProtocol(key), bound, snap.Counts[i]))
+		}
+		fields = append(fields, fmt.Sprintf("%s_count=%di", escapeLineProtocol(key), snap.Count))
+		fields = append(fields, fmt.Sprintf("%s_sum=%g", escapeLineProtocol(key), snap.Sum))
+		return strings.Join(fields, ",")
+	default:
+		var b strings.Builder
+		fmt.Fprintf(&b, "%s_count: %d, %s_sum: %g", key, snap.Count, key, snap.Sum)
+		for i, bound := range snap.Buckets {
+			fmt.Fprintf(&b, ", %s_bucket{le=%g}: %d", key, bound, snap.Counts[i])
+		}
+		return b.String()
+	}
+}
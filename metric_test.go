@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestHistogramEncodesBucketsInAllFormats guards against the JSON/text
+// encoders silently dropping the per-bucket breakdown that Prometheus and
+// line-protocol output already included.
+func TestHistogramEncodesBucketsInAllFormats(t *testing.T) {
+	snap := histogramSnapshot{
+		Buckets: []float64{0.1, 0.5, 1},
+		Counts:  []uint64{1, 2, 2},
+		Count:   3,
+		Sum:     2.35,
+	}
+
+	cases := []struct {
+		format Format
+		want   []string
+	}{
+		{FormatText, []string{"0.1", "0.5", "le="}},
+		{FormatJSON, []string{"0.1", "0.5", `"buckets"`, `"counts"`}},
+		{FormatPromText, []string{`le="0.1"`, `le="0.5"`, "_bucket"}},
+		{FormatLineProto, []string{"_bucket_0.1", "_bucket_0.5"}},
+	}
+
+	for _, c := range cases {
+		encoded := encodeHistogram("latency", snap, c.format)
+		for _, want := range c.want {
+			if !strings.Contains(encoded, want) {
+				t.Errorf("format %v: encoded histogram %q missing bucket breakdown %q", c.format, encoded, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Splits a key into its base metric name and an optional Prometheus label
+// suffix, so keys recorded as `name{label="v"}` render as dimensioned
+// samples under a single metric name.
+func splitPrometheusKey(k string) (name, labels string) {
+	if i := strings.IndexByte(k, '{'); i >= 0 {
+		return k[:i], k[i:]
+	}
+	return k, ""
+}
+
+// Merges an extra label into an existing `{...}` suffix (or starts a new
+// one), used to attach a histogram bucket's "le" label to any labels the
+// caller already recorded on the key.
+func withLabel(labels, key, value string) string {
+	pair := fmt.Sprintf(`%s="%s"`, key, value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// Writes the current metrics to w in the Prometheus text exposition format.
+func (s *Stats) Prometheus(w Writer) {
+	w.Write(s.snapshot().prometheus(""))
+}
+
+// Returns an http.Handler that serves the current metrics in Prometheus
+// exposition format, with metric names prefixed by namespace (e.g. "app"
+// produces "app_requests"). Mount it at whatever path the caller scrapes,
+// typically "/metrics".
+func (s *Stats) Handler(namespace string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(s.snapshot().prometheus(namespace))
+	})
+}
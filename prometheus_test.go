@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrometheusOutput(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 2)
+	s.SetGauge(`temp{region="east"}`, 98.6)
+
+	var buf bytes.Buffer
+	s.Prometheus(&buf)
+	out := buf.String()
+
+	if !strings.Contains(out, "# HELP hits hits\n# TYPE hits counter\nhits 2\n") {
+		t.Fatalf("Prometheus output missing counter HELP/TYPE/sample: %q", out)
+	}
+	if !strings.Contains(out, `temp{region="east"} 98.6`) {
+		t.Fatalf("Prometheus output missing labeled gauge sample: %q", out)
+	}
+	if strings.Contains(out, `temp{region="east"}{`) {
+		t.Fatalf("Prometheus output duplicated labels: %q", out)
+	}
+}
+
+func TestHandlerNamespaceAndContentType(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	s.Handler("app").ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Fatalf("Handler Content-Type = %q, want Prometheus exposition type", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "app_hits 1\n") {
+		t.Fatalf("Handler output missing namespaced metric: %q", body)
+	}
+}
+
+func TestSplitPrometheusKey(t *testing.T) {
+	name, labels := splitPrometheusKey(`requests{method="GET"}`)
+	if name != "requests" || labels != `{method="GET"}` {
+		t.Fatalf("splitPrometheusKey = (%q, %q), want (%q, %q)", name, labels, "requests", `{method="GET"}`)
+	}
+
+	name, labels = splitPrometheusKey("requests")
+	if name != "requests" || labels != "" {
+		t.Fatalf("splitPrometheusKey(%q) = (%q, %q), want no labels", "requests", name, labels)
+	}
+}
@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DurationMode controls how Snapshot's elapsed Duration is represented in
+// JSON output.
+type DurationMode int
+
+const (
+	DurationString DurationMode = iota
+	DurationNanos
+	DurationSeconds
+)
+
+// Snapshot is an immutable, point-in-time copy of a Stats instance's
+// metrics and elapsed duration. Stats takes one under its read lock for its
+// own encoders, and a Collector takes one each tick to hand to its Sinks
+// after the originating Stats has already been reset.
+type Snapshot struct {
+	Values   map[string]interface{} // per-key metric.Snapshot() results
+	Keys     []string               // Values' keys, already ordered per Stats.SortFunc
+	Duration time.Duration
+	Taken    time.Time
+
+	durationMode DurationMode
+	indentPrefix string
+	indent       string
+}
+
+// buildSnapshot assembles a Snapshot from the current state. Callers must
+// already hold s.mu, for either read or write. Keys are ordered by
+// s.SortFunc, or lexicographically if it is nil.
+func (s *Stats) buildSnapshot() Snapshot {
+	values := make(map[string]interface{}, len(s.metrics))
+	keys := make([]string, 0, len(s.metrics))
+	for k, m := range s.metrics {
+		values[k] = m.Snapshot()
+		keys = append(keys, k)
+	}
+	if s.SortFunc != nil {
+		s.SortFunc(keys)
+	} else {
+		sort.Strings(keys)
+	}
+
+	return Snapshot{
+		Values:       values,
+		Keys:         keys,
+		Duration:     s.elapsed(),
+		Taken:        time.Now(),
+		durationMode: s.DurationMode,
+		indentPrefix: s.indentPrefix,
+		indent:       s.indent,
+	}
+}
+
+// snapshot copies the current metric values and elapsed duration under a
+// read lock, so the result stays valid after s is later reset.
+func (s *Stats) snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.buildSnapshot()
+}
+
+// snapshotAndReset atomically copies the current metric values and elapsed
+// duration and clears them, all under a single write lock, so no
+// AddCounter/SetGauge/Observe landing between the copy and the clear is
+// lost. Used by Collector so a tick's snapshot and reset can't race with
+// concurrent writers.
+func (s *Stats) snapshotAndReset() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := s.buildSnapshot()
+	s.metrics = map[string]metric{}
+	s.start = time.Now()
+	return snap
+}
+
+// Snapshot returns an immutable copy of the current metrics and elapsed
+// duration, ordered per Stats.SortFunc, so callers can build their own
+// encoders on top of Stats without relying on Print, MarshalJSON,
+// Prometheus or LineProtocol.
+//
+// This returns a Snapshot rather than the originally requested
+// map[string]int: since chunk0-3 added gauges and histograms, a single
+// map[string]int can no longer hold every metric's value, so callers need
+// the per-kind typing Snapshot.Values carries.
+func (s *Stats) Snapshot() Snapshot {
+	return s.snapshot()
+}
+
+// Encodes the value stored at k in the given format, dispatching on its
+// underlying metric kind.
+func (snap Snapshot) encodeKey(k string, format Format) string {
+	switch v := snap.Values[k].(type) {
+	case int64:
+		return encodeCounter(k, v, format)
+	case float64:
+		return encodeGauge(k, v, format)
+	case histogramSnapshot:
+		return encodeHistogram(k, v, format)
+	default:
+		return ""
+	}
+}
+
+// jsonValue returns the value stored at k as a plain Go value suitable for
+// encoding/json to marshal.
+func (snap Snapshot) jsonValue(k string) interface{} {
+	switch v := snap.Values[k].(type) {
+	case int64, float64:
+		return v
+	case histogramSnapshot:
+		return map[string]interface{}{
+			"count":   v.Count,
+			"sum":     v.Sum,
+			"buckets": v.Buckets,
+			"counts":  v.Counts,
+		}
+	default:
+		return nil
+	}
+}
+
+// durationValue renders snap.Duration per snap.durationMode.
+func (snap Snapshot) durationValue() interface{} {
+	switch snap.durationMode {
+	case DurationNanos:
+		return snap.Duration.Nanoseconds()
+	case DurationSeconds:
+		return snap.Duration.Seconds()
+	default:
+		return snap.Duration.String()
+	}
+}
+
+// Encode renders the whole snapshot in the given format: FormatText mirrors
+// Print, FormatJSON mirrors MarshalJSON, FormatPromText mirrors Prometheus,
+// and FormatLineProto falls back to a bare metrics line (use LineProtocol
+// on Stats directly for a measurement and tags).
+func (snap Snapshot) Encode(format Format) []byte {
+	switch format {
+	case FormatJSON:
+		return snap.json()
+	case FormatPromText:
+		return snap.prometheus("")
+	case FormatLineProto:
+		return snap.lineProtocol("metrics", nil)
+	default:
+		return snap.text()
+	}
+}
+
+func (snap Snapshot) text() []byte {
+	var b strings.Builder
+	for _, k := range snap.Keys {
+		fmt.Fprintf(&b, "%s\n", snap.encodeKey(k, FormatText))
+	}
+	if len(snap.Keys) > 0 {
+		fmt.Fprintf(&b, "%s\n", snap.Duration)
+	}
+	return []byte(b.String())
+}
+
+// json renders the snapshot as a JSON object via encoding/json, preserving
+// snap.Keys' order (encoding/json sorts map keys, so each field is
+// marshaled individually and assembled in order) and honoring
+// snap.indentPrefix/indent if WithIndent was used.
+func (snap Snapshot) json() []byte {
+	lines := make([]string, 0, len(snap.Keys)+1)
+	for _, k := range snap.Keys {
+		lines = append(lines, jsonField(k, snap.jsonValue(k)))
+	}
+	if len(snap.Keys) > 0 {
+		lines = append(lines, jsonField("Duration", snap.durationValue()))
+	}
+
+	body := "{\n\t" + strings.Join(lines, ",\n\t")
+	if len(lines) > 0 {
+		body += "\n"
+	}
+	body += "}"
+
+	if snap.indentPrefix == "" && snap.indent == "" {
+		return []byte(body + "\n")
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(body), snap.indentPrefix, snap.indent); err != nil {
+		return []byte(body + "\n")
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// jsonField marshals a single "key": value pair via encoding/json.
+func jsonField(key string, value interface{}) string {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v = []byte("null")
+	}
+	return fmt.Sprintf("%s: %s", k, v)
+}
+
+func (snap Snapshot) prometheus(namespace string) []byte {
+	var b strings.Builder
+	seen := map[string]bool{}
+	for _, k := range snap.Keys {
+		base, labels := splitPrometheusKey(k)
+		name := base
+		if namespace != "" {
+			name = namespace + "_" + base
+		}
+
+		switch v := snap.Values[k].(type) {
+		case int64:
+			if !seen[name] {
+				seen[name] = true
+				fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, name, name)
+			}
+			fmt.Fprintf(&b, "%s%s %d\n", name, labels, v)
+		case float64:
+			if !seen[name] {
+				seen[name] = true
+				fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", name, name, name)
+			}
+			fmt.Fprintf(&b, "%s%s %g\n", name, labels, v)
+		case histogramSnapshot:
+			if !seen[name] {
+				seen[name] = true
+				fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", name, name, name)
+			}
+			for i, bound := range v.Buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(labels, "le", fmt.Sprintf("%g", bound)), v.Counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), v.Count)
+			fmt.Fprintf(&b, "%s_count%s %d\n", name, labels, v.Count)
+			fmt.Fprintf(&b, "%s_sum%s %g\n", name, labels, v.Sum)
+		}
+	}
+	return []byte(b.String())
+}
+
+func (snap Snapshot) lineProtocol(measurement string, tags map[string]string) []byte {
+	b := escapeLineProtocolMeasurement(measurement)
+	for _, k := range sortedTagKeys(tags) {
+		b += fmt.Sprintf(",%s=%s", escapeLineProtocol(k), escapeLineProtocol(tags[k]))
+	}
+
+	b += " "
+	fields := make([]string, 0, len(snap.Keys)+1)
+	for _, k := range snap.Keys {
+		fields = append(fields, snap.encodeKey(k, FormatLineProto))
+	}
+	fields = append(fields, fmt.Sprintf("duration_ns=%di", snap.Duration.Nanoseconds()))
+	b += strings.Join(fields, ",")
+
+	return []byte(fmt.Sprintf("%s %d\n", b, snap.Taken.UnixNano()))
+}
@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotOrdersKeysBySortFunc(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("b", 1)
+	s.AddCounter("a", 1)
+	s.AddCounter("c", 1)
+
+	s.SortFunc = func(keys []string) {
+		sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	}
+
+	snap := s.Snapshot()
+	want := []string{"c", "b", "a"}
+	if len(snap.Keys) != len(want) {
+		t.Fatalf("Snapshot().Keys = %v, want %v", snap.Keys, want)
+	}
+	for i, k := range want {
+		if snap.Keys[i] != k {
+			t.Fatalf("Snapshot().Keys = %v, want %v", snap.Keys, want)
+		}
+	}
+}
+
+func TestSnapshotDefaultSortIsLexicographic(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("b", 1)
+	s.AddCounter("a", 1)
+
+	snap := s.Snapshot()
+	if snap.Keys[0] != "a" || snap.Keys[1] != "b" {
+		t.Fatalf("Snapshot().Keys = %v, want lexicographic [a b]", snap.Keys)
+	}
+}
+
+func TestSnapshotValuesByKind(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 3)
+	s.SetGauge("temp", 98.6)
+	s.RegisterHistogram("latency", []float64{1, 2})
+	s.Observe("latency", 1.5)
+
+	snap := s.Snapshot()
+	if v, ok := snap.Values["hits"].(int64); !ok || v != 3 {
+		t.Fatalf("Snapshot().Values[%q] = %#v, want int64(3)", "hits", snap.Values["hits"])
+	}
+	if v, ok := snap.Values["temp"].(float64); !ok || v != 98.6 {
+		t.Fatalf("Snapshot().Values[%q] = %#v, want float64(98.6)", "temp", snap.Values["temp"])
+	}
+	if v, ok := snap.Values["latency"].(histogramSnapshot); !ok || v.Count != 1 {
+		t.Fatalf("Snapshot().Values[%q] = %#v, want a histogramSnapshot with Count 1", "latency", snap.Values["latency"])
+	}
+}
+
+func TestWithIndentIndentsJSON(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+	s.WithIndent("", "  ")
+
+	out, _ := s.MarshalJSON()
+	if !strings.Contains(string(out), "\n  \"hits\"") {
+		t.Fatalf("MarshalJSON() = %s, want two-space indented fields", out)
+	}
+}
+
+func TestDurationModeRendersJSONDuration(t *testing.T) {
+	s := &Stats{}
+	s.Reset()
+	s.AddCounter("hits", 1)
+	s.DurationMode = DurationNanos
+
+	out, _ := s.MarshalJSON()
+	if !strings.Contains(string(out), `"Duration": `) {
+		t.Fatalf("MarshalJSON() = %s, want a Duration field", out)
+	}
+	if strings.Contains(string(out), `"Duration": "`) {
+		t.Fatalf("MarshalJSON() = %s, want DurationNanos to render as a bare number, not a string", out)
+	}
+}
@@ -11,35 +11,116 @@ type Writer interface {
 	Write([]byte) (int, error)
 }
 
-// A utility structure to collect metrics concurrently.
+// A utility structure to collect metrics concurrently. The zero value is
+// ready to use.
 type Stats struct {
-	mu     sync.RWMutex
-	start  time.Time
-	keys   []string
-	values []int
+	mu      sync.RWMutex
+	start   time.Time
+	metrics map[string]metric
+
+	// SortFunc orders keys before they're rendered by Print, MarshalJSON,
+	// Prometheus and LineProtocol. A nil SortFunc sorts lexicographically.
+	SortFunc func([]string)
+
+	// DurationMode controls how the elapsed Duration is represented in
+	// MarshalJSON's output.
+	DurationMode DurationMode
+
+	indentPrefix string
+	indent       string
 }
 
-// Creates or updates a stored metric and returns its value.
+// WithIndent configures MarshalJSON to indent its output per
+// encoding/json.Indent, using the given prefix and indent strings. Passing
+// two empty strings, the default, keeps the built-in layout of one
+// tab-indented field per line.
+func (s *Stats) WithIndent(prefix, indent string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indentPrefix = prefix
+	s.indent = indent
+}
+
+// Lazily allocates metric storage. Callers must already hold s.mu.
+func (s *Stats) init() {
+	if s.metrics == nil {
+		s.metrics = map[string]metric{}
+	}
+}
+
+// Creates or updates a counter and returns its value. Retained for
+// backwards compatibility; behaves exactly like AddCounter.
 func (s *Stats) Add(k string, v int) int {
+	return int(s.AddCounter(k, int64(v)))
+}
+
+// Creates or updates a monotonically increasing counter and returns its
+// new value.
+func (s *Stats) AddCounter(k string, v int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	c, ok := s.metrics[k].(*counterMetric)
+	if !ok {
+		c = &counterMetric{}
+		s.metrics[k] = c
+	}
+	c.v += v
+	return c.v
+}
+
+// Sets a gauge to v, replacing its previous value, and returns v.
+func (s *Stats) SetGauge(k string, v float64) float64 {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for i := range s.keys {
-		if s.keys[i] == k {
-			s.values[i] += v
-			return s.values[i]
-		}
+	s.init()
+	g, ok := s.metrics[k].(*gaugeMetric)
+	if !ok {
+		g = &gaugeMetric{}
+		s.metrics[k] = g
+	}
+	g.v = v
+	return g.v
+}
+
+// Declares a histogram at k with the given bucket upper bounds. Calling it
+// again replaces the histogram and discards any prior observations; call
+// it before Observe or Time for that key.
+func (s *Stats) RegisterHistogram(k string, buckets []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	s.metrics[k] = newHistogramMetric(buckets)
+}
+
+// Records an observation against the histogram at k, registering one with
+// no buckets (count and sum only) if it wasn't already registered.
+func (s *Stats) Observe(k string, v float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.init()
+	h, ok := s.metrics[k].(*histogramMetric)
+	if !ok {
+		h = newHistogramMetric(nil)
+		s.metrics[k] = h
+	}
+	h.observe(v)
+}
+
+// Starts a timer and returns a function that records the elapsed time in
+// seconds into the histogram at k when called.
+func (s *Stats) Time(k string) func() {
+	start := time.Now()
+	return func() {
+		s.Observe(k, time.Since(start).Seconds())
 	}
-	s.keys = append(s.keys, k)
-	s.values = append(s.values, v)
-	return v
 }
 
 // Initializes metric storage and start time, clearing previous values.
 func (s *Stats) Reset() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.keys = []string{}
-	s.values = []int{}
+	s.metrics = map[string]metric{}
 	s.start = time.Now()
 }
 
@@ -47,6 +128,17 @@ func (s *Stats) Reset() {
 func (s *Stats) Duration() time.Duration {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.elapsed()
+}
+
+// elapsed returns the time since Reset was last called, or zero if Reset
+// has never been called, so a Stats zero value reports a sane duration
+// instead of saturating time.Since's zero-Time result. Callers must
+// already hold s.mu, for either read or write.
+func (s *Stats) elapsed() time.Duration {
+	if s.start.IsZero() {
+		return 0
+	}
 	return time.Since(s.start)
 }
 
@@ -54,14 +146,7 @@ func (s *Stats) Duration() time.Duration {
 //
 // If no metrics exist, then no output will be written.
 func (s *Stats) Print(w Writer) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	for i := range s.keys {
-		fmt.Fprintf(w, "%s: %d\n", s.keys[i], s.values[i])
-	}
-	if len(s.keys) > 0 {
-		fmt.Fprintf(w, "%s\n", s.Duration())
-	}
+	w.Write(s.snapshot().text())
 }
 
 // Uses a writer to print the json format.
@@ -71,22 +156,7 @@ func (s *Stats) Json(w Writer) {
 }
 
 // An override for json marshal which correctly prints all of the stored
-// key and value pairs, followed by execution time as "Duration".
+// metrics in sorted key order, followed by execution time as "Duration".
 func (s *Stats) MarshalJSON() ([]byte, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	b := []byte("{\n")
-	for i := range s.keys {
-		b = append(b, []byte("\t"+fmt.Sprintf(`"%s": %d`, s.keys[i], s.values[i]))...)
-		if len(b) > 1 {
-			b = append(b, []byte(",")...)
-		}
-		b = append(b, []byte("\n")...)
-	}
-	if len(s.keys) > 0 {
-		b = append(b, []byte("\t"+fmt.Sprintf(`"Duration": "%s"`, s.Duration())+"\n")...)
-	}
-	b = append(b, []byte("}\n")...)
-	return b, nil
+	return s.snapshot().json(), nil
 }
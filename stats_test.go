@@ -0,0 +1,24 @@
+package metrics
+
+import "testing"
+
+// TestZeroValueDurationIsZero guards against the "zero value is ready to
+// use" doc comment being a lie: before Reset is ever called, Duration (and
+// anything built on it, like LineProtocol's duration_ns field) used to
+// report time.Since of the zero time.Time, i.e. the saturated max duration.
+func TestZeroValueDurationIsZero(t *testing.T) {
+	var s Stats
+	if d := s.Duration(); d != 0 {
+		t.Fatalf("Duration() on a zero-value Stats = %s, want 0", d)
+	}
+
+	s.AddCounter("hits", 1)
+	if d := s.Duration(); d != 0 {
+		t.Fatalf("Duration() after AddCounter without Reset = %s, want 0", d)
+	}
+
+	s.Reset()
+	if d := s.Duration(); d < 0 {
+		t.Fatalf("Duration() after Reset = %s, want >= 0", d)
+	}
+}